@@ -0,0 +1,13 @@
+package wallet
+
+// Balance holds an amount of coins and coin hours
+type Balance struct {
+	Coins uint64
+	Hours uint64
+}
+
+// BalancePair records a confirmed and predicted balance
+type BalancePair struct {
+	Confirmed Balance
+	Predicted Balance
+}
@@ -0,0 +1,276 @@
+package wallet
+
+import (
+	"crypto/hmac"
+	"crypto/sha512"
+	"encoding/binary"
+	"encoding/hex"
+	"fmt"
+	"math/big"
+
+	"github.com/skycoin/skycoin/src/cipher"
+)
+
+// bip44HardenedOffset is added to an index to produce a hardened child number, per BIP32
+const bip44HardenedOffset = uint32(0x80000000)
+
+// bip44Purpose is the BIP44 purpose constant (the first hardened level of the path)
+const bip44Purpose = uint32(44)
+
+// secp256k1Order is the order of the secp256k1 curve's base point, used to
+// reduce derived private key scalars modulo n as required by BIP32 CKDpriv.
+var secp256k1Order, _ = new(big.Int).SetString("FFFFFFFFFFFFFFFFFFFFFFFFFFFFFFFEBAAEDCE6AF48A03BBFD25E8CD0364141", 16)
+
+// Bip44 meta keys, stored alongside the common Meta keys in Wallet.Meta
+const metaBip44Coin = "bip44Coin"
+
+// extendedKey is a minimal BIP32 extended private key: a 32-byte key plus a 32-byte chain code
+type extendedKey struct {
+	key       []byte // 32 bytes
+	chainCode []byte // 32 bytes
+}
+
+// deriveChild derives the child extended private key at the given index, following
+// BIP32 CKDpriv via HMAC-SHA512. Indices >= bip44HardenedOffset use hardened derivation.
+func (k *extendedKey) deriveChild(index uint32) (*extendedKey, error) {
+	var data []byte
+	if index >= bip44HardenedOffset {
+		// hardened: 0x00 || ser256(kpar) || ser32(index)
+		data = append([]byte{0x00}, k.key...)
+	} else {
+		// normal: serP(point(kpar)) || ser32(index)
+		sk := cipher.MustNewSecKey(k.key)
+		pk, err := cipher.PubKeyFromSecKey(sk)
+		if err != nil {
+			return nil, fmt.Errorf("derive public key for non-hardened child failed: %v", err)
+		}
+		data = append([]byte{}, pk[:]...)
+	}
+
+	idxBytes := make([]byte, 4)
+	binary.BigEndian.PutUint32(idxBytes, index)
+	data = append(data, idxBytes...)
+
+	mac := hmac.New(sha512.New, k.chainCode)
+	if _, err := mac.Write(data); err != nil {
+		return nil, err
+	}
+	sum := mac.Sum(nil)
+
+	il, ir := sum[:32], sum[32:]
+
+	childKey := addModN(k.key, il)
+	if childKey == nil {
+		return nil, fmt.Errorf("derived key is invalid, index %d must be skipped", index)
+	}
+
+	return &extendedKey{key: childKey, chainCode: ir}, nil
+}
+
+// addModN computes (a + b) mod secp256k1Order, returning nil if b is not below
+// the curve order or the result is zero (per BIP32, such indices must be skipped).
+func addModN(a, b []byte) []byte {
+	x := new(big.Int).SetBytes(a)
+	y := new(big.Int).SetBytes(b)
+	if y.Cmp(secp256k1Order) >= 0 {
+		return nil
+	}
+
+	sum := new(big.Int).Add(x, y)
+	sum.Mod(sum, secp256k1Order)
+
+	if sum.Sign() == 0 {
+		return nil
+	}
+
+	out := make([]byte, 32)
+	sum.FillBytes(out)
+	return out
+}
+
+// masterKeyFromSeed derives the BIP32 master extended key from a binary seed
+func masterKeyFromSeed(seed []byte) *extendedKey {
+	mac := hmac.New(sha512.New, []byte("Bitcoin seed"))
+	mac.Write(seed) //nolint:errcheck
+	sum := mac.Sum(nil)
+	return &extendedKey{key: sum[:32], chainCode: sum[32:]}
+}
+
+// bip44Path derives the account-level extended key for m/44'/coinType'/account'
+func bip44Path(master *extendedKey, coinType, account uint32) (*extendedKey, error) {
+	purpose, err := master.deriveChild(bip44Purpose + bip44HardenedOffset)
+	if err != nil {
+		return nil, fmt.Errorf("derive purpose level failed: %v", err)
+	}
+
+	coin, err := purpose.deriveChild(coinType + bip44HardenedOffset)
+	if err != nil {
+		return nil, fmt.Errorf("derive coin_type level failed: %v", err)
+	}
+
+	acct, err := coin.deriveChild(account + bip44HardenedOffset)
+	if err != nil {
+		return nil, fmt.Errorf("derive account level failed: %v", err)
+	}
+
+	return acct, nil
+}
+
+// NewBip44WalletScanAhead creates a BIP44 wallet (m/44'/coin'/account'/change/index),
+// generating the first external address of account 0.
+func NewBip44WalletScanAhead(wltName string, opts Options, bg BalanceGetter) (*Wallet, error) {
+	seed := opts.Seed
+	seedType := SeedTypeDeterministic
+	mnemonic := ""
+
+	if opts.Mnemonic != "" {
+		s, err := deriveBip39Seed(opts.Mnemonic, opts.Passphrase)
+		if err != nil {
+			return nil, err
+		}
+		seed = s
+		seedType = SeedTypeBip39
+		mnemonic = opts.Mnemonic
+	}
+
+	if seed == "" {
+		return nil, fmt.Errorf("seed is required")
+	}
+
+	w := &Wallet{
+		Meta: map[string]string{
+			metaFilename:  wltName,
+			metaVersion:   Version,
+			metaLabel:     opts.Label,
+			metaSeed:      seed,
+			metaSeedType:  seedType,
+			metaType:      WalletTypeBip44,
+			metaCoin:      opts.Coin,
+			metaBip44Coin: fmt.Sprintf("%d", opts.CoinType),
+			metaEncrypted: "false",
+		},
+	}
+
+	if mnemonic != "" {
+		w.Meta[metaMnemonic] = mnemonic
+	}
+
+	seedBytes, err := w.seedBytes()
+	if err != nil {
+		return nil, fmt.Errorf("invalid seed: %v", err)
+	}
+	master := masterKeyFromSeed(seedBytes)
+
+	if _, err := w.bip44DeriveAddresses(master, 0, 0, 1); err != nil {
+		return nil, err
+	}
+
+	if opts.Encrypt {
+		if len(opts.Password) == 0 {
+			return nil, ErrMissingPassword
+		}
+		if err := w.Lock(opts.Password, opts.CryptoType); err != nil {
+			return nil, err
+		}
+	}
+
+	return w, nil
+}
+
+// bip44DeriveAddresses derives num addresses for the given account/change chain,
+// starting after any addresses already generated for that chain.
+func (w *Wallet) bip44DeriveAddresses(master *extendedKey, account, change uint32, num uint64) ([]cipher.Address, error) {
+	acctKey, err := bip44Path(master, w.bip44CoinType(), account)
+	if err != nil {
+		return nil, err
+	}
+
+	changeKey, err := acctKey.deriveChild(change)
+	if err != nil {
+		return nil, fmt.Errorf("derive change level failed: %v", err)
+	}
+
+	start := uint32(w.bip44ChainLen(account, change))
+
+	addrs := make([]cipher.Address, 0, num)
+	for i := uint64(0); i < num; i++ {
+		childIdx := start + uint32(i)
+		childKey, err := changeKey.deriveChild(childIdx)
+		if err != nil {
+			return nil, fmt.Errorf("derive address index %d failed: %v", childIdx, err)
+		}
+
+		sk := cipher.MustNewSecKey(childKey.key)
+		pk := cipher.MustPubKeyFromSecKey(sk)
+
+		entry := Entry{
+			Address:     cipher.AddressFromPubKey(pk),
+			Public:      pk,
+			Secret:      sk,
+			Account:     account,
+			Change:      change,
+			ChildNumber: childIdx,
+		}
+		w.Entries = append(w.Entries, entry)
+		addrs = append(addrs, entry.Address)
+	}
+
+	return addrs, nil
+}
+
+func (w *Wallet) bip44CoinType() uint32 {
+	var coinType uint32
+	fmt.Sscanf(w.Meta[metaBip44Coin], "%d", &coinType) //nolint:errcheck
+	return coinType
+}
+
+// bip44ChainLen returns how many addresses already exist for the given account/change chain
+func (w *Wallet) bip44ChainLen(account, change uint32) int {
+	n := 0
+	for _, e := range w.Entries {
+		if e.Account == account && e.Change == change {
+			n++
+		}
+	}
+	return n
+}
+
+// bip44AddressesFiltered returns the addresses in the wallet for a given account/change,
+// used by Service.GetAddresses to filter a BIP44 wallet's entries.
+func (w *Wallet) bip44AddressesFiltered(account, change uint32) []cipher.Address {
+	var addrs []cipher.Address
+	for _, e := range w.Entries {
+		if e.Account == account && e.Change == change {
+			addrs = append(addrs, e.Address)
+		}
+	}
+	return addrs
+}
+
+// fingerprint returns a stable identifier for duplicate detection: for BIP44
+// wallets this is derived from the account-0 external chain's first address,
+// keyed by coin type so wallets on different BIP44 coin types never collide;
+// for xpub wallets it is the serialized extended public key; for other wallet
+// types it is simply the first entry's address.
+func (w *Wallet) fingerprint() (string, error) {
+	if len(w.Entries) == 0 {
+		return "", fmt.Errorf("wallet has no entries")
+	}
+
+	switch w.Type() {
+	case WalletTypeBip44:
+		// fall through to the bip44-specific lookup below
+	case WalletTypeXPub:
+		return "xpub:" + w.Meta[metaXPub], nil
+	default:
+		return w.Entries[0].Address.String(), nil
+	}
+
+	for _, e := range w.Entries {
+		if e.Account == 0 && e.Change == 0 && e.ChildNumber == 0 {
+			return hex.EncodeToString([]byte(w.Meta[metaBip44Coin])) + ":" + e.Address.String(), nil
+		}
+	}
+
+	return "", fmt.Errorf("bip44 wallet is missing its account 0 external chain address")
+}
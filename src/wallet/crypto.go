@@ -0,0 +1,9 @@
+package wallet
+
+// CryptoType represents the encryption algorithm used to encrypt a wallet's seed data
+type CryptoType string
+
+// Crypto types
+const (
+	CryptoTypeScryptChacha20poly1305 CryptoType = "scrypt-chacha20poly1305"
+)
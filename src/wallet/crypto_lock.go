@@ -0,0 +1,267 @@
+package wallet
+
+import (
+	stdcipher "crypto/cipher"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+
+	"golang.org/x/crypto/chacha20poly1305"
+	"golang.org/x/crypto/scrypt"
+
+	"github.com/skycoin/skycoin/src/cipher"
+)
+
+// scrypt parameters for deriving the chacha20poly1305 key from a wallet password.
+// N=2^15 costs ~32MB of memory and a noticeable fraction of a second per attempt
+// on commodity hardware, which is the usual interactive-unlock tradeoff for scrypt.
+const (
+	scryptN       = 1 << 15
+	scryptR       = 8
+	scryptP       = 1
+	scryptKeyLen  = chacha20poly1305.KeySize
+	scryptSaltLen = 32
+)
+
+// Lock encrypts the wallet's seed data in place with password, using the given crypto type
+func (w *Wallet) Lock(password []byte, cryptoType CryptoType) error {
+	if len(password) == 0 {
+		return ErrMissingPassword
+	}
+	if w.IsEncrypted() {
+		return ErrWalletEncrypted
+	}
+
+	locked, err := w.lockWith(password, cryptoType)
+	if err != nil {
+		return err
+	}
+
+	*w = *locked
+	return nil
+}
+
+// lockWith returns a new, encrypted copy of the wallet, leaving w untouched
+func (w *Wallet) lockWith(password []byte, cryptoType CryptoType) (*Wallet, error) {
+	nw := w.clone()
+
+	sd := &secrets{
+		seed:     nw.Meta[metaSeed],
+		lastSeed: nw.Meta[metaLastSeed],
+		mnemonic: nw.Meta[metaMnemonic],
+	}
+	if w.secrets != nil {
+		sd = &secrets{seed: w.secrets.seed, lastSeed: w.secrets.lastSeed, mnemonic: w.secrets.mnemonic}
+	}
+
+	// Capture each entry's private key into sd and scrub it from nw, so an
+	// "encrypted" wallet with addresses already generated doesn't still carry
+	// cleartext private keys in Entries when it's saved. An entry's key comes
+	// from nw.Entries itself if it was generated since the last unlock
+	// (GenerateAddresses always writes the key straight into Entries), or
+	// otherwise from w.secrets.entrySecrets, which is where Unlock leaves the
+	// keys of entries that already existed when the wallet was unlocked.
+	sd.entrySecrets = make([]cipher.SecKey, len(nw.Entries))
+	for i := range nw.Entries {
+		switch {
+		case nw.Entries[i].Secret != (cipher.SecKey{}):
+			sd.entrySecrets[i] = nw.Entries[i].Secret
+		case w.secrets != nil && i < len(w.secrets.entrySecrets):
+			sd.entrySecrets[i] = w.secrets.entrySecrets[i]
+		}
+		nw.Entries[i].Secret = cipher.SecKey{}
+	}
+
+	ciphertext, err := encryptSecrets(sd, password, cryptoType)
+	if err != nil {
+		return nil, fmt.Errorf("failed to encrypt wallet secrets: %v", err)
+	}
+
+	delete(nw.Meta, metaSeed)
+	delete(nw.Meta, metaLastSeed)
+	delete(nw.Meta, metaMnemonic)
+	nw.Meta[metaEncrypted] = "true"
+	nw.Meta[metaCryptoType] = string(cryptoType)
+	nw.Meta[metaSecrets] = hex.EncodeToString(ciphertext)
+	nw.secrets = nil
+
+	return nw, nil
+}
+
+// Unlock returns a decrypted copy of the wallet, leaving w untouched. The
+// returned wallet still reports IsEncrypted() == true and its Meta/Entries
+// are unchanged from w's: its plaintext seed, mnemonic and entry private keys
+// live only in the returned wallet's secrets field, which seed(), displaySeed()
+// and GenerateAddresses() already know to prefer over Meta/Entries when set.
+// This means a transiently-unlocked wallet is safe even if it's accidentally
+// Save()d: only the still-encrypted Meta/Entries get written. Callers that
+// need the decrypted wallet persisted to disk in plaintext (see
+// Service.DecryptWallet) call decryptInPlace on the result.
+func (w *Wallet) Unlock(password []byte) (*Wallet, error) {
+	if !w.IsEncrypted() {
+		return nil, ErrWalletNotEncrypted
+	}
+	if len(password) == 0 {
+		return nil, ErrMissingPassword
+	}
+
+	ciphertext, err := hex.DecodeString(w.Meta[metaSecrets])
+	if err != nil {
+		return nil, fmt.Errorf("invalid wallet secrets encoding: %v", err)
+	}
+
+	sd, err := decryptSecrets(ciphertext, password, CryptoType(w.Meta[metaCryptoType]))
+	if err != nil {
+		return nil, err
+	}
+
+	nw := w.clone()
+	nw.secrets = sd
+	return nw, nil
+}
+
+// decryptInPlace permanently materializes an unlocked wallet's secrets into
+// Meta and Entries and marks it as no longer encrypted. Only Service.DecryptWallet
+// calls this: it's the one caller that means for the wallet's plaintext to be
+// saved to disk. GuardUpdate, GuardView and wallet sessions only ever need the
+// seed/keys transiently, and read them straight off w.secrets instead.
+func (w *Wallet) decryptInPlace() {
+	delete(w.Meta, metaSecrets)
+	w.Meta[metaEncrypted] = "false"
+	w.Meta[metaSeed] = w.secrets.seed
+	w.Meta[metaLastSeed] = w.secrets.lastSeed
+	if w.secrets.mnemonic != "" {
+		w.Meta[metaMnemonic] = w.secrets.mnemonic
+	}
+	for i := range w.Entries {
+		if i < len(w.secrets.entrySecrets) {
+			w.Entries[i].Secret = w.secrets.entrySecrets[i]
+		}
+	}
+	w.secrets = nil
+}
+
+// secretsJSON is the plaintext serialization of a wallet's secrets, sealed in
+// place by encryptSecrets/decryptSecrets.
+type secretsJSON struct {
+	// Seed and LastSeed are hex-encoded: they may hold arbitrary binary data
+	// (e.g. the raw output of a deterministic key generator), which would be
+	// corrupted by encoding/json's requirement that string fields be valid UTF-8.
+	Seed         string   `json:"seed"`
+	LastSeed     string   `json:"lastSeed"`
+	Mnemonic     string   `json:"mnemonic,omitempty"`
+	EntrySecrets []string `json:"entrySecrets,omitempty"` // hex-encoded private keys, one per Wallet.Entries
+}
+
+// encryptSecrets serializes a wallet's secrets to JSON and seals them with
+// chacha20poly1305, keyed by scrypt(password, salt). The returned ciphertext
+// is salt || nonce || sealed, since both are needed to derive the same key
+// and open the seal again at decrypt time.
+func encryptSecrets(sd *secrets, password []byte, cryptoType CryptoType) ([]byte, error) {
+	if cryptoType != CryptoTypeScryptChacha20poly1305 {
+		return nil, fmt.Errorf("unsupported crypto type %q", cryptoType)
+	}
+
+	entrySecrets := make([]string, len(sd.entrySecrets))
+	for i, sk := range sd.entrySecrets {
+		entrySecrets[i] = hex.EncodeToString(sk[:])
+	}
+
+	plaintext, err := json.Marshal(secretsJSON{
+		Seed:         hex.EncodeToString([]byte(sd.seed)),
+		LastSeed:     hex.EncodeToString([]byte(sd.lastSeed)),
+		Mnemonic:     sd.mnemonic,
+		EntrySecrets: entrySecrets,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	salt := make([]byte, scryptSaltLen)
+	if _, err := rand.Read(salt); err != nil {
+		return nil, err
+	}
+
+	aead, err := newSecretsAEAD(password, salt)
+	if err != nil {
+		return nil, err
+	}
+
+	nonce := make([]byte, aead.NonceSize())
+	if _, err := rand.Read(nonce); err != nil {
+		return nil, err
+	}
+
+	sealed := aead.Seal(nil, nonce, plaintext, nil)
+
+	out := make([]byte, 0, len(salt)+len(nonce)+len(sealed))
+	out = append(out, salt...)
+	out = append(out, nonce...)
+	out = append(out, sealed...)
+	return out, nil
+}
+
+// decryptSecrets is the inverse of encryptSecrets.
+func decryptSecrets(ciphertext, password []byte, cryptoType CryptoType) (*secrets, error) {
+	if cryptoType != CryptoTypeScryptChacha20poly1305 {
+		return nil, fmt.Errorf("unsupported crypto type %q", cryptoType)
+	}
+
+	if len(ciphertext) < scryptSaltLen+chacha20poly1305.NonceSize {
+		return nil, ErrInvalidPassword
+	}
+
+	salt := ciphertext[:scryptSaltLen]
+	nonce := ciphertext[scryptSaltLen : scryptSaltLen+chacha20poly1305.NonceSize]
+	sealed := ciphertext[scryptSaltLen+chacha20poly1305.NonceSize:]
+
+	aead, err := newSecretsAEAD(password, salt)
+	if err != nil {
+		return nil, err
+	}
+
+	plaintext, err := aead.Open(nil, nonce, sealed, nil)
+	if err != nil {
+		return nil, ErrInvalidPassword
+	}
+
+	var sj secretsJSON
+	if err := json.Unmarshal(plaintext, &sj); err != nil {
+		return nil, ErrInvalidPassword
+	}
+
+	seed, err := hex.DecodeString(sj.Seed)
+	if err != nil {
+		return nil, ErrInvalidPassword
+	}
+	lastSeed, err := hex.DecodeString(sj.LastSeed)
+	if err != nil {
+		return nil, ErrInvalidPassword
+	}
+
+	entrySecrets := make([]cipher.SecKey, len(sj.EntrySecrets))
+	for i, s := range sj.EntrySecrets {
+		b, err := hex.DecodeString(s)
+		if err != nil {
+			return nil, ErrInvalidPassword
+		}
+		entrySecrets[i] = cipher.MustNewSecKey(b)
+	}
+
+	return &secrets{
+		seed:         string(seed),
+		lastSeed:     string(lastSeed),
+		mnemonic:     sj.Mnemonic,
+		entrySecrets: entrySecrets,
+	}, nil
+}
+
+// newSecretsAEAD derives a chacha20poly1305 AEAD from password and salt via scrypt
+func newSecretsAEAD(password, salt []byte) (stdcipher.AEAD, error) {
+	key, err := scrypt.Key(password, salt, scryptN, scryptR, scryptP, scryptKeyLen)
+	if err != nil {
+		return nil, err
+	}
+	return chacha20poly1305.New(key)
+}
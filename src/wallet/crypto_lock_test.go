@@ -0,0 +1,95 @@
+package wallet
+
+import "testing"
+
+// TestLockUnlockRoundTripsMnemonicAndLastSeed is a regression test for a bug
+// where encryptSecrets/decryptSecrets only ever round-tripped seed+lastSeed
+// concatenated with "|", silently losing the mnemonic (and not even
+// splitting lastSeed back out) on every lock/unlock.
+func TestLockUnlockRoundTripsMnemonicAndLastSeed(t *testing.T) {
+	const mnemonic = "abandon abandon abandon abandon abandon abandon abandon abandon abandon abandon abandon about"
+
+	w, err := newWallet("test.wlt", Options{Mnemonic: mnemonic})
+	if err != nil {
+		t.Fatalf("newWallet failed: %v", err)
+	}
+	if _, err := w.GenerateAddresses(2); err != nil {
+		t.Fatalf("GenerateAddresses failed: %v", err)
+	}
+
+	lastSeedBefore := w.Meta[metaLastSeed]
+
+	password := []byte("pwd")
+	if err := w.Lock(password, CryptoTypeScryptChacha20poly1305); err != nil {
+		t.Fatalf("Lock failed: %v", err)
+	}
+
+	unlocked, err := w.Unlock(password)
+	if err != nil {
+		t.Fatalf("Unlock failed: %v", err)
+	}
+
+	if unlocked.displaySeed() != mnemonic {
+		t.Fatalf("mnemonic not preserved through lock/unlock: got %q", unlocked.displaySeed())
+	}
+
+	if unlocked.secrets.lastSeed != lastSeedBefore {
+		t.Fatal("lastSeed not preserved through lock/unlock")
+	}
+
+	// Unlock only borrows the wallet's secrets transiently: Meta/Entries stay
+	// exactly as locked, so the wallet is still reported as encrypted.
+	if !unlocked.IsEncrypted() {
+		t.Fatal("Unlock must not flip the returned wallet's encrypted flag")
+	}
+	if unlocked.Meta[metaLastSeed] != "" {
+		t.Fatal("Unlock must not write the decrypted lastSeed into Meta")
+	}
+
+	if _, err := w.Unlock([]byte("wrong password")); err == nil {
+		t.Fatal("Unlock with the wrong password should fail")
+	}
+}
+
+// TestDecryptInPlaceMaterializesSecretsIntoMeta is a regression test for a bug
+// where Unlock wrote decrypted secrets straight into the wallet's exported,
+// disk-serialized Meta/Entries fields, meaning a transiently-unlocked wallet
+// (e.g. a wallet session) held plaintext private keys in exported fields
+// rather than the unexported secrets field meant to hold them.
+func TestDecryptInPlaceMaterializesSecretsIntoMeta(t *testing.T) {
+	w, err := newWallet("test.wlt", Options{Seed: "a seed"})
+	if err != nil {
+		t.Fatalf("newWallet failed: %v", err)
+	}
+	if _, err := w.GenerateAddresses(1); err != nil {
+		t.Fatalf("GenerateAddresses failed: %v", err)
+	}
+
+	seedBefore := w.Meta[metaSeed]
+	secretBefore := w.Entries[0].Secret
+
+	password := []byte("pwd")
+	if err := w.Lock(password, CryptoTypeScryptChacha20poly1305); err != nil {
+		t.Fatalf("Lock failed: %v", err)
+	}
+
+	unlocked, err := w.Unlock(password)
+	if err != nil {
+		t.Fatalf("Unlock failed: %v", err)
+	}
+
+	unlocked.decryptInPlace()
+
+	if unlocked.IsEncrypted() {
+		t.Fatal("decryptInPlace must clear the encrypted flag")
+	}
+	if unlocked.Meta[metaSeed] != seedBefore {
+		t.Fatal("decryptInPlace must restore the seed into Meta")
+	}
+	if unlocked.Entries[0].Secret != secretBefore {
+		t.Fatal("decryptInPlace must restore entry secrets")
+	}
+	if unlocked.secrets != nil {
+		t.Fatal("decryptInPlace must clear the wallet's secrets field once materialized")
+	}
+}
@@ -0,0 +1,19 @@
+package wallet
+
+import (
+	"github.com/skycoin/skycoin/src/cipher"
+)
+
+// Entry represents a keypair in a wallet, with its generated address
+type Entry struct {
+	Address cipher.Address
+	Public  cipher.PubKey
+	Secret  cipher.SecKey
+
+	// Account, Change and ChildNumber are populated for BIP44 wallets
+	// (Wallet.Type() == WalletTypeBip44); xpub wallets populate ChildNumber
+	// only. They are the zero value otherwise.
+	Account     uint32
+	Change      uint32
+	ChildNumber uint32
+}
@@ -0,0 +1,37 @@
+package wallet
+
+import "errors"
+
+// Error wrapping types for wallet service errors
+var (
+	// ErrWalletAPIDisabled is returned when the wallet API is disabled
+	ErrWalletAPIDisabled = errors.New("wallet api is disabled")
+	// ErrSeedAPIDisabled is returned when the seed API is disabled
+	ErrSeedAPIDisabled = errors.New("wallet seed api is disabled")
+	// ErrWalletNotExist is returned if a wallet does not exist
+	ErrWalletNotExist = errors.New("wallet doesn't exist")
+	// ErrSeedUsed is returned if a wallet already exists with the same seed
+	ErrSeedUsed = errors.New("wallet already exists with this seed")
+	// ErrWalletEncrypted is returned when trying to encrypt a wallet that is already encrypted
+	ErrWalletEncrypted = errors.New("wallet is encrypted")
+	// ErrWalletNotEncrypted is returned when trying to decrypt a wallet that is not encrypted
+	ErrWalletNotEncrypted = errors.New("wallet is not encrypted")
+	// ErrMissingPassword is returned when trying to operate an encrypted wallet without providing a password
+	ErrMissingPassword = errors.New("missing password")
+	// ErrInvalidPassword is returned when decrypting a wallet's secrets with the wrong password
+	ErrInvalidPassword = errors.New("invalid password")
+	// ErrWalletNotBip44 is returned when a bip44-only operation is attempted on a non-bip44 wallet
+	ErrWalletNotBip44 = errors.New("wallet is not a bip44 wallet")
+	// ErrWalletIsBip44 is returned when an operation that doesn't understand bip44's
+	// multi-account/change derivation chains (e.g. NewAddresses) is attempted on a
+	// bip44 wallet; use the *Bip44 variant of the operation instead
+	ErrWalletIsBip44 = errors.New("wallet is a bip44 wallet; use the Bip44 variant of this operation")
+	// ErrWalletIsWatchOnly is returned when an operation requiring a seed or private keys
+	// is attempted on a watch-only (xpub) wallet
+	ErrWalletIsWatchOnly = errors.New("wallet is watch-only and holds no seed or private keys")
+	// ErrSessionNotExist is returned when a session token is unknown or has expired
+	ErrSessionNotExist = errors.New("wallet unlock session doesn't exist or has expired")
+	// ErrWalletLockTimeout is returned when a wallet file's advisory lock could not be
+	// acquired before Config.OpenTimeout elapsed, e.g. because another process has it open
+	ErrWalletLockTimeout = errors.New("timed out waiting to acquire wallet file lock")
+)
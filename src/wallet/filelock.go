@@ -0,0 +1,28 @@
+package wallet
+
+import "time"
+
+// lockFile attempts to acquire an advisory, exclusive lock on the file at
+// path, retrying until timeout elapses. The returned unlock func releases the
+// lock and must always be called once the caller is done with the file.
+// Platform-specific locking primitives live in filelock_unix.go (syscall.Flock)
+// and filelock_windows.go (LockFileEx).
+//
+// tryLockFile is the non-blocking primitive each platform implements; lockFile
+// polls it so callers get a consistent timeout-based API across platforms.
+func lockFile(path string, timeout time.Duration) (func() error, error) {
+	deadline := time.Now().Add(timeout)
+
+	for {
+		unlock, err := tryLockFile(path)
+		if err == nil {
+			return unlock, nil
+		}
+
+		if time.Now().After(deadline) {
+			return nil, ErrWalletLockTimeout
+		}
+
+		time.Sleep(50 * time.Millisecond)
+	}
+}
@@ -0,0 +1,37 @@
+//go:build !windows
+// +build !windows
+
+package wallet
+
+import (
+	"os"
+	"syscall"
+)
+
+// lockFD takes a single, non-blocking advisory exclusive lock on an
+// already-open file via flock(2), so callers that need read-write access
+// (unlike tryLockFile, which only ever opens read-only) can still take the
+// same lock other processes check for.
+func lockFD(f *os.File) error {
+	return syscall.Flock(int(f.Fd()), syscall.LOCK_EX|syscall.LOCK_NB)
+}
+
+// tryLockFile attempts a single, non-blocking advisory exclusive lock via flock(2).
+func tryLockFile(path string) (func() error, error) {
+	f, err := os.OpenFile(path, os.O_RDONLY|os.O_CREATE, os.FileMode(0600))
+	if err != nil {
+		return nil, err
+	}
+
+	if err := lockFD(f); err != nil {
+		f.Close() //nolint:errcheck
+		return nil, err
+	}
+
+	unlock := func() error {
+		defer f.Close() //nolint:errcheck
+		return syscall.Flock(int(f.Fd()), syscall.LOCK_UN)
+	}
+
+	return unlock, nil
+}
@@ -0,0 +1,41 @@
+//go:build windows
+// +build windows
+
+package wallet
+
+import (
+	"os"
+
+	"golang.org/x/sys/windows"
+)
+
+// lockFD takes a single, non-blocking advisory exclusive lock on an
+// already-open file via LockFileEx, so callers that need read-write access
+// (unlike tryLockFile, which only ever opens read-only) can still take the
+// same lock other processes check for.
+func lockFD(f *os.File) error {
+	ol := new(windows.Overlapped)
+	return windows.LockFileEx(windows.Handle(f.Fd()), windows.LOCKFILE_EXCLUSIVE_LOCK|windows.LOCKFILE_FAIL_IMMEDIATELY, 0, 1, 0, ol)
+}
+
+// tryLockFile attempts a single, non-blocking advisory exclusive lock via LockFileEx.
+func tryLockFile(path string) (func() error, error) {
+	f, err := os.OpenFile(path, os.O_RDONLY|os.O_CREATE, os.FileMode(0600))
+	if err != nil {
+		return nil, err
+	}
+
+	ol := new(windows.Overlapped)
+	err = windows.LockFileEx(windows.Handle(f.Fd()), windows.LOCKFILE_EXCLUSIVE_LOCK|windows.LOCKFILE_FAIL_IMMEDIATELY, 0, 1, 0, ol)
+	if err != nil {
+		f.Close() //nolint:errcheck
+		return nil, err
+	}
+
+	unlock := func() error {
+		defer f.Close() //nolint:errcheck
+		return windows.UnlockFileEx(windows.Handle(f.Fd()), 0, 1, 0, ol)
+	}
+
+	return unlock, nil
+}
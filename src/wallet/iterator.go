@@ -0,0 +1,117 @@
+package wallet
+
+import (
+	"sort"
+	"strings"
+)
+
+// ListOptions filters and paginates the wallets returned by Service.ListWallets
+type ListOptions struct {
+	LabelContains string // only include wallets whose label contains this substring
+	Encrypted     *bool  // nil: don't filter; otherwise only include wallets matching this
+	Type          string // "" : don't filter; otherwise only include wallets of this WalletType
+	Limit         int    // 0: unlimited
+	Offset        int
+}
+
+func (opts ListOptions) match(w *Wallet) bool {
+	if opts.LabelContains != "" && !strings.Contains(w.Label(), opts.LabelContains) {
+		return false
+	}
+	if opts.Encrypted != nil && w.IsEncrypted() != *opts.Encrypted {
+		return false
+	}
+	if opts.Type != "" && w.Type() != opts.Type {
+		return false
+	}
+	return true
+}
+
+// WalletIterator is a snapshot, read-only view over a Service's wallets,
+// filtered and paginated by ListOptions. Unlike Service.GetWallets, it does
+// not clone every wallet up front; each wallet is only cloned when Value() is
+// called, so memory use is bounded by page size rather than the total wallet count.
+type WalletIterator struct {
+	serv    *Service
+	ids     []string
+	opts    ListOptions
+	pos     int
+	skipped int
+	yielded int
+	current string
+}
+
+// ListWallets returns a snapshot iterator over the service's wallets, filtered
+// and paginated according to opts.
+func (serv *Service) ListWallets(opts ListOptions) *WalletIterator {
+	serv.RLock()
+	ids := make([]string, 0, len(serv.wallets))
+	for id := range serv.wallets {
+		ids = append(ids, id)
+	}
+	serv.RUnlock()
+
+	// Deterministic ordering, since map iteration order is not
+	sort.Strings(ids)
+
+	return &WalletIterator{
+		serv: serv,
+		ids:  ids,
+		opts: opts,
+	}
+}
+
+// Next advances the iterator to the next matching wallet, returning false once
+// there are no more matches (respecting Limit, if set).
+func (it *WalletIterator) Next() bool {
+	if it.opts.Limit > 0 && it.yielded >= it.opts.Limit {
+		return false
+	}
+
+	it.serv.RLock()
+	defer it.serv.RUnlock()
+
+	for it.pos < len(it.ids) {
+		id := it.ids[it.pos]
+		it.pos++
+
+		w, ok := it.serv.wallets.get(id)
+		if !ok {
+			// removed since the snapshot was taken
+			continue
+		}
+
+		if !it.opts.match(w) {
+			continue
+		}
+
+		if it.skipped < it.opts.Offset {
+			it.skipped++
+			continue
+		}
+
+		it.current = id
+		it.yielded++
+		return true
+	}
+
+	return false
+}
+
+// Value returns a clone of the wallet at the iterator's current position.
+// Must only be called after a call to Next() that returned true.
+func (it *WalletIterator) Value() *Wallet {
+	it.serv.RLock()
+	defer it.serv.RUnlock()
+
+	w, ok := it.serv.wallets.get(it.current)
+	if !ok {
+		return nil
+	}
+	return w.clone()
+}
+
+// Close releases the iterator. It holds no locks or resources between calls
+// to Next/Value, so this is currently a no-op; it exists so callers can defer
+// it without caring whether that remains true.
+func (it *WalletIterator) Close() {}
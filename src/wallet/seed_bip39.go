@@ -0,0 +1,65 @@
+package wallet
+
+import (
+	"encoding/hex"
+	"fmt"
+
+	"github.com/tyler-smith/go-bip39"
+)
+
+// metaSeedType identifies how Meta[metaSeed] should be interpreted: the empty
+// value (or "deterministic") means it's a raw seed string, "bip39" means it's
+// the hex-encoded 64-byte seed derived from a mnemonic.
+const metaSeedType = "seedType"
+
+// seed types
+const (
+	SeedTypeDeterministic = "deterministic"
+	SeedTypeBip39         = "bip39"
+)
+
+// metaMnemonic stores the bip39 mnemonic itself, so GetWalletSeed can return
+// the human-readable phrase rather than the derived binary seed
+const metaMnemonic = "mnemonic"
+
+// ErrInvalidMnemonic is returned when a mnemonic fails the bip39 checksum validation
+var ErrInvalidMnemonic = fmt.Errorf("mnemonic is not a valid bip39 mnemonic")
+
+// GenSeed generates a new bip39 mnemonic. entropyBits must be one of
+// 128/160/192/224/256, producing a 12/15/18/21/24 word mnemonic respectively.
+// If userEntropy is provided, it is mixed (XORed) into the crypto/rand-sourced
+// entropy before the mnemonic is derived, so a caller can contribute additional
+// randomness without it being the sole source of entropy.
+func (serv *Service) GenSeed(entropyBits int, userEntropy []byte) (string, error) {
+	entropy, err := bip39.NewEntropy(entropyBits)
+	if err != nil {
+		return "", err
+	}
+
+	if len(userEntropy) > 0 {
+		for i := range entropy {
+			entropy[i] ^= userEntropy[i%len(userEntropy)]
+		}
+	}
+
+	return bip39.NewMnemonic(entropy)
+}
+
+// ValidateMnemonic returns an error if mnemonic is not a valid bip39 mnemonic
+func (serv *Service) ValidateMnemonic(mnemonic string) error {
+	if !bip39.IsMnemonicValid(mnemonic) {
+		return ErrInvalidMnemonic
+	}
+	return nil
+}
+
+// deriveBip39Seed validates mnemonic and derives the 64-byte wallet seed
+// from it via PBKDF2-HMAC-SHA512, returning it hex-encoded for storage in Meta[metaSeed].
+func deriveBip39Seed(mnemonic, passphrase string) (string, error) {
+	if !bip39.IsMnemonicValid(mnemonic) {
+		return "", ErrInvalidMnemonic
+	}
+
+	seed := bip39.NewSeed(mnemonic, passphrase)
+	return hex.EncodeToString(seed), nil
+}
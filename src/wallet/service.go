@@ -1,14 +1,19 @@
 package wallet
 
 import (
+	"context"
 	"fmt"
 	"os"
 	"sync"
+	"time"
 
 	"github.com/skycoin/skycoin/src/cipher"
 	"github.com/skycoin/skycoin/src/coin"
 )
 
+// defaultOpenTimeout is used when Config.OpenTimeout is zero
+const defaultOpenTimeout = 60 * time.Second
+
 // BalanceGetter interface for getting the balance of given addresses
 type BalanceGetter interface {
 	GetBalanceOfAddrs(addrs []cipher.Address) ([]BalancePair, error)
@@ -23,6 +28,11 @@ type Service struct {
 	cryptoType      CryptoType
 	enableWalletAPI bool
 	enableSeedAPI   bool
+	openTimeout     time.Duration
+	fileLock        bool
+	sessions        map[string]*session // Key: session token; Value: cached unlocked wallet
+	synced          bool
+	syncSubscribers []chan struct{}
 }
 
 // Config wallet service config
@@ -31,15 +41,36 @@ type Config struct {
 	CryptoType      CryptoType
 	EnableWalletAPI bool
 	EnableSeedAPI   bool
+	SyncNotifier    SyncNotifier // optional; wires up Service.SubscribeSynced/IsSynced
+
+	// OpenTimeout bounds how long to wait to acquire a wallet file's advisory
+	// lock when FileLock is set. Defaults to 60 seconds if zero.
+	OpenTimeout time.Duration
+	// FileLock causes wallet file reads to acquire an advisory, exclusive lock
+	// before reading, so a .wlt file held open by another process (e.g. a GUI
+	// wallet running against the same WalletDir) can't be read mid-write.
+	FileLock bool
 }
 
 // NewService new wallet service
 func NewService(c Config) (*Service, error) {
+	openTimeout := c.OpenTimeout
+	if openTimeout <= 0 {
+		openTimeout = defaultOpenTimeout
+	}
+
 	serv := &Service{
 		firstAddrIDMap:  make(map[string]string),
 		cryptoType:      c.CryptoType,
 		enableWalletAPI: c.EnableWalletAPI,
 		enableSeedAPI:   c.EnableSeedAPI,
+		openTimeout:     openTimeout,
+		fileLock:        c.FileLock,
+		sessions:        make(map[string]*session),
+	}
+
+	if c.SyncNotifier != nil {
+		go serv.watchSyncNotifier(context.Background(), c.SyncNotifier)
 	}
 
 	if !serv.enableWalletAPI {
@@ -58,7 +89,7 @@ func NewService(c Config) (*Service, error) {
 	}
 
 	// Loads wallets
-	w, err := LoadWallets(serv.walletDirectory)
+	w, err := LoadWallets(serv.walletDirectory, serv.openTimeout, serv.fileLock)
 	if err != nil {
 		return nil, fmt.Errorf("failed to load all wallets: %v", err)
 	}
@@ -90,13 +121,28 @@ func (serv *Service) loadWallet(wltName string, options Options, bg BalanceGette
 		options.CryptoType = serv.cryptoType
 	}
 
-	w, err := NewWalletScanAhead(wltName, options, bg)
+	var w *Wallet
+	var err error
+	switch options.Type {
+	case WalletTypeBip44:
+		w, err = NewBip44WalletScanAhead(wltName, options, bg)
+	case WalletTypeXPub:
+		w, err = NewXPubWallet(wltName, options)
+	default:
+		w, err = NewWalletScanAhead(wltName, options, bg)
+	}
 	if err != nil {
 		return nil, err
 	}
 
-	// Check for duplicate wallets by initial seed
-	if _, ok := serv.firstAddrIDMap[w.Entries[0].Address.String()]; ok {
+	// Check for duplicate wallets by fingerprint (for bip44 wallets, this is
+	// derived from the account 0 external chain rather than the raw first address)
+	fp, err := w.fingerprint()
+	if err != nil {
+		return nil, err
+	}
+
+	if _, ok := serv.firstAddrIDMap[fp]; ok {
 		return nil, ErrSeedUsed
 	}
 
@@ -110,7 +156,7 @@ func (serv *Service) loadWallet(wltName string, options Options, bg BalanceGette
 		return nil, err
 	}
 
-	serv.firstAddrIDMap[w.Entries[0].Address.String()] = w.Filename()
+	serv.firstAddrIDMap[fp] = w.Filename()
 
 	return w.clone(), nil
 }
@@ -140,6 +186,10 @@ func (serv *Service) EncryptWallet(wltID string, password []byte) (*Wallet, erro
 		return nil, err
 	}
 
+	if w.IsWatchOnly() {
+		return nil, ErrWalletIsWatchOnly
+	}
+
 	if w.IsEncrypted() {
 		return nil, ErrWalletEncrypted
 	}
@@ -176,11 +226,13 @@ func (serv *Service) DecryptWallet(wltID string, password []byte) (*Wallet, erro
 		return nil, ErrWalletNotEncrypted
 	}
 
-	// Unlocks the wallet
+	// Unlocks the wallet and permanently materializes its secrets into Meta/Entries,
+	// since this wallet is about to be saved to disk in plaintext
 	unlockWlt, err := w.Unlock(password)
 	if err != nil {
 		return nil, err
 	}
+	unlockWlt.decryptInPlace()
 
 	// Updates the wallet file
 	if err := unlockWlt.Save(serv.walletDirectory); err != nil {
@@ -208,10 +260,69 @@ func (serv *Service) NewAddresses(wltID string, password []byte, num uint64) ([]
 		return nil, err
 	}
 
+	if w.Type() == WalletTypeBip44 {
+		return nil, ErrWalletIsBip44
+	}
+
 	var addrs []cipher.Address
 	f := func(wlt *Wallet) error {
 		var err error
-		addrs, err = wlt.GenerateAddresses(num)
+		if wlt.Type() == WalletTypeXPub {
+			addrs, err = wlt.xpubDeriveAddresses(num)
+		} else {
+			addrs, err = wlt.GenerateAddresses(num)
+		}
+		return err
+	}
+
+	if w.IsEncrypted() {
+		if err := w.GuardUpdate(password, f); err != nil {
+			return nil, err
+		}
+	} else {
+		if err := f(w); err != nil {
+			return nil, err
+		}
+	}
+
+	// Set the updated wallet back
+	serv.wallets.set(w)
+
+	if err := w.Save(serv.walletDirectory); err != nil {
+		return []cipher.Address{}, err
+	}
+
+	return addrs, nil
+}
+
+// NewAddressesBip44 generates num address entries on the given account/change
+// chain of a bip44 wallet, returning ErrWalletNotBip44 for other wallet types.
+// Set password as nil if the wallet is not encrypted, otherwise the password must be provided.
+func (serv *Service) NewAddressesBip44(wltID string, password []byte, account, change uint32, num uint64) ([]cipher.Address, error) {
+	serv.Lock()
+	defer serv.Unlock()
+
+	if !serv.enableWalletAPI {
+		return nil, ErrWalletAPIDisabled
+	}
+
+	w, err := serv.getWallet(wltID)
+	if err != nil {
+		return nil, err
+	}
+
+	if w.Type() != WalletTypeBip44 {
+		return nil, ErrWalletNotBip44
+	}
+
+	var addrs []cipher.Address
+	f := func(wlt *Wallet) error {
+		seedBytes, err := wlt.seedBytes()
+		if err != nil {
+			return err
+		}
+		master := masterKeyFromSeed(seedBytes)
+		addrs, err = wlt.bip44DeriveAddresses(master, account, change, num)
 		return err
 	}
 
@@ -251,6 +362,27 @@ func (serv *Service) GetAddresses(wltID string) ([]cipher.Address, error) {
 	return w.GetAddresses(), nil
 }
 
+// GetAddressesBip44 returns the addresses on the given account/change chain of
+// a bip44 wallet, returning ErrWalletNotBip44 for other wallet types.
+func (serv *Service) GetAddressesBip44(wltID string, account, change uint32) ([]cipher.Address, error) {
+	serv.RLock()
+	defer serv.RUnlock()
+	if !serv.enableWalletAPI {
+		return nil, ErrWalletAPIDisabled
+	}
+
+	w, err := serv.getWallet(wltID)
+	if err != nil {
+		return nil, err
+	}
+
+	if w.Type() != WalletTypeBip44 {
+		return nil, ErrWalletNotBip44
+	}
+
+	return w.bip44AddressesFiltered(account, change), nil
+}
+
 // GetWallet returns wallet by id
 func (serv *Service) GetWallet(wltID string) (*Wallet, error) {
 	serv.RLock()
@@ -293,13 +425,18 @@ func (serv *Service) ReloadWallets() error {
 	if !serv.enableWalletAPI {
 		return ErrWalletAPIDisabled
 	}
-	wallets, err := LoadWallets(serv.walletDirectory)
+	wallets, err := LoadWallets(serv.walletDirectory, serv.openTimeout, serv.fileLock)
 	if err != nil {
 		return err
 	}
 
 	serv.firstAddrIDMap = make(map[string]string)
 	serv.wallets = serv.removeDup(wallets)
+
+	// Reloaded wallet state may no longer match what's cached in memory, so
+	// any outstanding unlock sessions are no longer valid.
+	serv.invalidateSessionsLocked()
+
 	return nil
 }
 
@@ -317,6 +454,10 @@ func (serv *Service) CreateAndSignTransaction(wltID string, password []byte, aux
 		return nil, err
 	}
 
+	if w.IsWatchOnly() {
+		return nil, ErrWalletIsWatchOnly
+	}
+
 	var tx *coin.Transaction
 	f := func(wlt *Wallet) error {
 		var err error
@@ -355,6 +496,10 @@ func (serv *Service) CreateAndSignTransactionAdvanced(params CreateTransactionPa
 		return nil, nil, err
 	}
 
+	if w.IsWatchOnly() {
+		return nil, nil, ErrWalletIsWatchOnly
+	}
+
 	// Check if the wallet needs a password
 	if w.IsEncrypted() {
 		if len(params.Wallet.Password) == 0 {
@@ -426,8 +571,15 @@ func (serv *Service) removeDup(wlts Wallets) Wallets {
 			continue
 		}
 
-		addr := wlt.Entries[0].Address.String()
-		id, ok := serv.firstAddrIDMap[addr]
+		fp, err := wlt.fingerprint()
+		if err != nil {
+			// wallet has entries but no recognizable fingerprint (e.g. a bip44
+			// wallet missing its account 0 external chain); treat as empty
+			rmWltIDS = append(rmWltIDS, wltID)
+			continue
+		}
+
+		id, ok := serv.firstAddrIDMap[fp]
 
 		if ok {
 			// check whose entries number is bigger
@@ -442,11 +594,11 @@ func (serv *Service) removeDup(wlts Wallets) Wallets {
 			// records the wallet id that need to remove
 			rmWltIDS = append(rmWltIDS, id)
 			// update wallet id
-			serv.firstAddrIDMap[addr] = wltID
+			serv.firstAddrIDMap[fp] = wltID
 			continue
 		}
 
-		serv.firstAddrIDMap[addr] = wltID
+		serv.firstAddrIDMap[fp] = wltID
 	}
 
 	// remove the duplicate and empty wallet
@@ -475,13 +627,17 @@ func (serv *Service) GetWalletSeed(wltID string, password []byte) (string, error
 		return "", err
 	}
 
+	if w.IsWatchOnly() {
+		return "", ErrWalletIsWatchOnly
+	}
+
 	if !w.IsEncrypted() {
 		return "", ErrWalletNotEncrypted
 	}
 
 	var seed string
 	if err := w.GuardView(password, func(wlt *Wallet) error {
-		seed = wlt.seed()
+		seed = wlt.displaySeed()
 		return nil
 	}); err != nil {
 		return "", err
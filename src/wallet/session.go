@@ -0,0 +1,272 @@
+package wallet
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"time"
+
+	"github.com/skycoin/skycoin/src/cipher"
+	"github.com/skycoin/skycoin/src/coin"
+)
+
+// defaultSessionTimeout is used when Unlock is called with a zero timeout
+const defaultSessionTimeout = 10 * time.Minute
+
+// session holds a decrypted wallet in memory for the duration of a user
+// session. It is never persisted to disk; ReloadWallets and LockSession/expiry
+// discard it, which zeroes out its keys.
+type session struct {
+	walletID string
+	wallet   *Wallet // decrypted clone; holds the plaintext seed/keys
+	password []byte  // cached so modifications can be re-encrypted and saved without re-prompting
+	timer    *time.Timer
+}
+
+// zero overwrites the session's cached password and scrubs the decrypted
+// wallet's key material, so neither lingers in memory after eviction. seed/
+// lastSeed/mnemonic are plain strings, which Go can't zero in place; dropping
+// the reference so GC can reclaim the backing array is the most that can be
+// done for them. Entry secrets and entrySecrets are fixed-size arrays, which
+// are zeroed in place.
+func (s *session) zero() {
+	for i := range s.password {
+		s.password[i] = 0
+	}
+
+	if s.wallet == nil {
+		return
+	}
+
+	for i := range s.wallet.Entries {
+		s.wallet.Entries[i].Secret = cipher.SecKey{}
+	}
+
+	if sd := s.wallet.secrets; sd != nil {
+		sd.seed = ""
+		sd.lastSeed = ""
+		sd.mnemonic = ""
+		for i := range sd.entrySecrets {
+			sd.entrySecrets[i] = cipher.SecKey{}
+		}
+	}
+}
+
+// UnlockSession decrypts wltID with password and caches the decrypted wallet in
+// memory for timeout, returning an opaque session token that NewAddressesWithSession,
+// CreateAndSignTransactionWithSession and CreateAndSignTransactionAdvancedWithSession
+// can be given instead of the password. This avoids callers having to hold the
+// plaintext password in memory for the duration of a user session.
+// Named UnlockSession (not Unlock) to avoid shadowing the embedded sync.RWMutex's
+// Unlock method that the rest of Service relies on for locking.
+func (serv *Service) UnlockSession(wltID string, password []byte, timeout time.Duration) (string, error) {
+	serv.Lock()
+	defer serv.Unlock()
+
+	if !serv.enableWalletAPI {
+		return "", ErrWalletAPIDisabled
+	}
+
+	w, ok := serv.wallets.get(wltID)
+	if !ok {
+		return "", ErrWalletNotExist
+	}
+
+	if !w.IsEncrypted() {
+		return "", ErrWalletNotEncrypted
+	}
+
+	unlocked, err := w.Unlock(password)
+	if err != nil {
+		return "", err
+	}
+
+	if timeout <= 0 {
+		timeout = defaultSessionTimeout
+	}
+
+	sessionID, err := newSessionID()
+	if err != nil {
+		return "", err
+	}
+
+	s := &session{
+		walletID: wltID,
+		wallet:   unlocked,
+		password: append([]byte{}, password...),
+	}
+	s.timer = time.AfterFunc(timeout, func() {
+		serv.expireSession(sessionID)
+	})
+
+	serv.sessions[sessionID] = s
+
+	return sessionID, nil
+}
+
+// LockSession evicts and zeroes the decrypted wallet cached for wltID, if any.
+func (serv *Service) LockSession(wltID string) error {
+	serv.Lock()
+	defer serv.Unlock()
+
+	if !serv.enableWalletAPI {
+		return ErrWalletAPIDisabled
+	}
+
+	for id, s := range serv.sessions {
+		if s.walletID == wltID {
+			serv.removeSessionLocked(id)
+		}
+	}
+
+	return nil
+}
+
+// Touch resets an outstanding session's expiry to timeout from now
+// (defaultSessionTimeout if timeout is not positive), keeping the decrypted
+// wallet alive for callers that are still active.
+func (serv *Service) Touch(sessionID string, timeout time.Duration) error {
+	serv.Lock()
+	defer serv.Unlock()
+
+	if !serv.enableWalletAPI {
+		return ErrWalletAPIDisabled
+	}
+
+	s, ok := serv.sessions[sessionID]
+	if !ok {
+		return ErrSessionNotExist
+	}
+
+	if timeout <= 0 {
+		timeout = defaultSessionTimeout
+	}
+
+	s.timer.Reset(timeout)
+	return nil
+}
+
+// expireSession is invoked by the session's timer when it fires
+func (serv *Service) expireSession(sessionID string) {
+	serv.Lock()
+	defer serv.Unlock()
+	serv.removeSessionLocked(sessionID)
+}
+
+// removeSessionLocked deletes a session; caller must hold serv's lock.
+func (serv *Service) removeSessionLocked(sessionID string) {
+	if s, ok := serv.sessions[sessionID]; ok {
+		s.timer.Stop()
+		s.zero()
+		delete(serv.sessions, sessionID)
+	}
+}
+
+// invalidateSessionsLocked discards all outstanding sessions; caller must hold serv's lock.
+func (serv *Service) invalidateSessionsLocked() {
+	for id, s := range serv.sessions {
+		s.timer.Stop()
+		s.zero()
+		delete(serv.sessions, id)
+	}
+}
+
+func (serv *Service) getSession(sessionID string) (*session, error) {
+	s, ok := serv.sessions[sessionID]
+	if !ok {
+		return nil, ErrSessionNotExist
+	}
+	return s, nil
+}
+
+func newSessionID() (string, error) {
+	b := make([]byte, 16)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(b), nil
+}
+
+// NewAddressesWithSession is equivalent to NewAddresses, but authenticates
+// against an outstanding Unlock session instead of a password.
+func (serv *Service) NewAddressesWithSession(sessionID string, num uint64) ([]cipher.Address, error) {
+	serv.Lock()
+	defer serv.Unlock()
+
+	if !serv.enableWalletAPI {
+		return nil, ErrWalletAPIDisabled
+	}
+
+	s, err := serv.getSession(sessionID)
+	if err != nil {
+		return nil, err
+	}
+
+	if s.wallet.Type() == WalletTypeBip44 {
+		return nil, ErrWalletIsBip44
+	}
+
+	var addrs []cipher.Address
+	if s.wallet.Type() == WalletTypeXPub {
+		addrs, err = s.wallet.xpubDeriveAddresses(num)
+	} else {
+		addrs, err = s.wallet.GenerateAddresses(num)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	// Re-lock the persisted wallet with the session's cached password and save it
+	w, ok := serv.wallets.get(s.wallet.Filename())
+	if !ok {
+		return nil, ErrWalletNotExist
+	}
+
+	locked, err := s.wallet.lockWith(s.password, CryptoType(w.Meta[metaCryptoType]))
+	if err != nil {
+		return nil, err
+	}
+	serv.wallets.set(locked)
+
+	if err := locked.Save(serv.walletDirectory); err != nil {
+		return nil, err
+	}
+
+	return addrs, nil
+}
+
+// CreateAndSignTransactionWithSession is equivalent to CreateAndSignTransaction,
+// but authenticates against an outstanding Unlock session instead of a password.
+func (serv *Service) CreateAndSignTransactionWithSession(sessionID string, auxs coin.AddressUxOuts, headTime, coins uint64, dest cipher.Address) (*coin.Transaction, error) {
+	serv.Lock()
+	defer serv.Unlock()
+
+	if !serv.enableWalletAPI {
+		return nil, ErrWalletAPIDisabled
+	}
+
+	s, err := serv.getSession(sessionID)
+	if err != nil {
+		return nil, err
+	}
+
+	return s.wallet.CreateAndSignTransaction(auxs, headTime, coins, dest)
+}
+
+// CreateAndSignTransactionAdvancedWithSession is equivalent to
+// CreateAndSignTransactionAdvanced, but authenticates against an outstanding
+// Unlock session instead of a password.
+func (serv *Service) CreateAndSignTransactionAdvancedWithSession(sessionID string, params CreateTransactionParams, auxs coin.AddressUxOuts, headTime uint64) (*coin.Transaction, []UxBalance, error) {
+	serv.Lock()
+	defer serv.Unlock()
+
+	if !serv.enableWalletAPI {
+		return nil, nil, ErrWalletAPIDisabled
+	}
+
+	s, err := serv.getSession(sessionID)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	return s.wallet.CreateAndSignTransactionAdvanced(params, auxs, headTime)
+}
@@ -0,0 +1,80 @@
+package wallet
+
+import "context"
+
+// SyncNotifier is implemented by the node component backing a Service's
+// BalanceGetter, so the service can tell callers when balances it returns are
+// derived from a fully-synced head rather than one that is still catching up.
+// The shape mirrors the GetSyncedUpdate(ctx) <-chan struct{} pattern used by
+// the Ark wallet interface.
+type SyncNotifier interface {
+	// GetSyncedUpdate returns a channel that receives a value each time the
+	// node transitions to synced. It is closed when ctx is canceled.
+	GetSyncedUpdate(ctx context.Context) <-chan struct{}
+}
+
+// watchSyncNotifier runs for the life of the service, updating serv.synced and
+// fanning out to subscribers each time the notifier reports a sync transition.
+func (serv *Service) watchSyncNotifier(ctx context.Context, sn SyncNotifier) {
+	updates := sn.GetSyncedUpdate(ctx)
+	for {
+		select {
+		case _, ok := <-updates:
+			if !ok {
+				return
+			}
+			serv.setSynced()
+		case <-ctx.Done():
+			return
+		}
+	}
+}
+
+func (serv *Service) setSynced() {
+	serv.Lock()
+	defer serv.Unlock()
+
+	serv.synced = true
+
+	for _, c := range serv.syncSubscribers {
+		select {
+		case c <- struct{}{}:
+		default:
+			// a slow consumer must not stall the notifier; it will simply
+			// miss this ping, IsSynced() remains authoritative
+		}
+	}
+}
+
+// IsSynced returns a snapshot of whether the node backing this service is synced.
+// Always returns false if the service was not constructed with a SyncNotifier.
+func (serv *Service) IsSynced() bool {
+	serv.RLock()
+	defer serv.RUnlock()
+	return serv.synced
+}
+
+// SubscribeSynced returns a channel that receives a value each time the node
+// backing this service transitions to synced, and an unsubscribe func that
+// must be called to release the subscription. Sends to the channel are
+// non-blocking, so a slow consumer cannot stall notification of other subscribers.
+func (serv *Service) SubscribeSynced() (<-chan struct{}, func()) {
+	serv.Lock()
+	defer serv.Unlock()
+
+	c := make(chan struct{}, 1)
+	serv.syncSubscribers = append(serv.syncSubscribers, c)
+
+	unsubscribe := func() {
+		serv.Lock()
+		defer serv.Unlock()
+		for i, sub := range serv.syncSubscribers {
+			if sub == c {
+				serv.syncSubscribers = append(serv.syncSubscribers[:i], serv.syncSubscribers[i+1:]...)
+				break
+			}
+		}
+	}
+
+	return c, unsubscribe
+}
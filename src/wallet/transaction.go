@@ -0,0 +1,46 @@
+package wallet
+
+import (
+	"fmt"
+
+	"github.com/skycoin/skycoin/src/cipher"
+)
+
+// CreateTransactionWalletParams defines the wallet that funds a created transaction
+type CreateTransactionWalletParams struct {
+	ID        string
+	Password  []byte
+	Addresses []cipher.Address
+}
+
+// CreateTransactionParams defines the parameters for creating a transaction from a wallet
+type CreateTransactionParams struct {
+	Wallet CreateTransactionWalletParams
+	To     []coinTo
+}
+
+type coinTo struct {
+	Address cipher.Address
+	Coins   uint64
+	Hours   uint64
+}
+
+// Validate validates the parameters
+func (p CreateTransactionParams) Validate() error {
+	if p.Wallet.ID == "" {
+		return fmt.Errorf("wallet ID is required")
+	}
+	if len(p.To) == 0 {
+		return fmt.Errorf("to is required")
+	}
+	return nil
+}
+
+// UxBalance is an intermediate representation of a UxOut for wallet transaction creation
+type UxBalance struct {
+	Hash    string
+	BkSeq   uint64
+	Address cipher.Address
+	Coins   uint64
+	Hours   uint64
+}
@@ -0,0 +1,372 @@
+package wallet
+
+import (
+	"encoding/hex"
+	"fmt"
+	"strconv"
+	"time"
+
+	"github.com/skycoin/skycoin/src/cipher"
+	"github.com/skycoin/skycoin/src/coin"
+)
+
+// Wallet version
+const Version = "0.2"
+
+// Meta keys
+const (
+	metaVersion    = "version"
+	metaFilename   = "filename"
+	metaLabel      = "label"
+	metaSeed       = "seed"
+	metaLastSeed   = "lastSeed"
+	metaType       = "type"
+	metaCoin       = "coin"
+	metaEncrypted  = "encrypted"
+	metaCryptoType = "cryptoType"
+	metaSecrets    = "secrets"
+	metaTimestamp  = "tm"
+)
+
+// Wallet types
+const (
+	// WalletTypeDeterministic is the original, single-chain deterministic wallet
+	WalletTypeDeterministic = "deterministic"
+	// WalletTypeBip44 is a BIP44 hierarchical-deterministic wallet with multiple accounts
+	WalletTypeBip44 = "bip44"
+	// WalletTypeXPub is a watch-only wallet that derives addresses from an imported extended public key
+	WalletTypeXPub = "xpub"
+)
+
+// Options are the parameters used to create a new wallet via Service.CreateWallet
+type Options struct {
+	Type       string // wallet type, e.g. WalletTypeDeterministic, WalletTypeBip44 or WalletTypeXPub
+	Coin       string
+	CoinType   uint32 // BIP44 coin type, used when Type is WalletTypeBip44 or WalletTypeXPub
+	Label      string
+	Seed       string
+	Mnemonic   string // bip39 mnemonic; when set, takes precedence over Seed
+	Passphrase string // optional bip39 passphrase, used only alongside Mnemonic
+	Encrypt    bool
+	Password   []byte
+	CryptoType CryptoType
+	ScanN      uint64 // number of addresses to scan ahead looking for a balance
+
+	// XPub and XPubPathPrefix are only used when Type is WalletTypeXPub
+	XPub           string // serialized extended public key to import
+	XPubPathPrefix string // e.g. "m/44'/8000'/0'/0"; defaults to the bip44 external chain of account 0
+}
+
+// Wallet holds a set of deterministic keys, encryption metadata, and the
+// generated address entries derived from the wallet's seed.
+type Wallet struct {
+	Meta    map[string]string
+	Entries []Entry
+	secrets *secrets // decrypted secrets; nil unless the wallet is unlocked in memory
+}
+
+// secrets holds the plaintext seed material of an unlocked wallet.
+// This must never be written to disk directly.
+type secrets struct {
+	seed     string
+	lastSeed string
+	mnemonic string // only populated for bip39-seeded wallets
+
+	// entrySecrets holds the plaintext private key for each entry in
+	// Wallet.Entries, in the same order, so Lock can scrub Entry.Secret
+	// before the wallet is saved and Unlock can restore it.
+	entrySecrets []cipher.SecKey
+}
+
+// newWalletFilename generates a default wallet filename based on the current time
+func newWalletFilename() string {
+	return fmt.Sprintf("%d_%s.wlt", time.Now().Unix(), "skycoin")
+}
+
+// NewWalletScanAhead creates a deterministic wallet, generating the first
+// address and then scanning ahead for addresses with a non-zero balance.
+// Encryption is applied last, after all addresses are derived: Lock wipes
+// Meta[metaLastSeed] and would otherwise leave GenerateAddresses deriving
+// from an empty seed.
+func NewWalletScanAhead(wltName string, opts Options, bg BalanceGetter) (*Wallet, error) {
+	w, err := newWallet(wltName, opts)
+	if err != nil {
+		return nil, err
+	}
+
+	if _, err := w.GenerateAddresses(1); err != nil {
+		return nil, err
+	}
+
+	if bg != nil && opts.ScanN > 1 {
+		if err := scanAddresses(w, opts.ScanN, bg); err != nil {
+			return nil, err
+		}
+	}
+
+	if opts.Encrypt {
+		if len(opts.Password) == 0 {
+			return nil, ErrMissingPassword
+		}
+		if err := w.Lock(opts.Password, opts.CryptoType); err != nil {
+			return nil, err
+		}
+	}
+
+	return w, nil
+}
+
+func newWallet(wltName string, opts Options) (*Wallet, error) {
+	seed := opts.Seed
+	seedType := SeedTypeDeterministic
+	mnemonic := ""
+
+	if opts.Mnemonic != "" {
+		s, err := deriveBip39Seed(opts.Mnemonic, opts.Passphrase)
+		if err != nil {
+			return nil, err
+		}
+		seed = s
+		seedType = SeedTypeBip39
+		mnemonic = opts.Mnemonic
+	}
+
+	if seed == "" {
+		return nil, fmt.Errorf("seed is required")
+	}
+
+	wltType := opts.Type
+	if wltType == "" {
+		wltType = WalletTypeDeterministic
+	}
+
+	w := &Wallet{
+		Meta: map[string]string{
+			metaFilename:  wltName,
+			metaVersion:   Version,
+			metaLabel:     opts.Label,
+			metaSeed:      seed,
+			metaLastSeed:  seed,
+			metaSeedType:  seedType,
+			metaType:      wltType,
+			metaCoin:      opts.Coin,
+			metaTimestamp: strconv.FormatInt(time.Now().Unix(), 10),
+			metaEncrypted: "false",
+		},
+	}
+
+	if mnemonic != "" {
+		w.Meta[metaMnemonic] = mnemonic
+	}
+
+	return w, nil
+}
+
+// scanAddresses generates addresses ahead of the wallet's current index,
+// keeping only up to the last address with a non-zero balance
+func scanAddresses(w *Wallet, scanN uint64, bg BalanceGetter) error {
+	nExist := uint64(len(w.Entries))
+	if scanN <= nExist {
+		return nil
+	}
+
+	addrs, err := w.GenerateAddresses(scanN - nExist)
+	if err != nil {
+		return err
+	}
+
+	balances, err := bg.GetBalanceOfAddrs(addrs)
+	if err != nil {
+		return err
+	}
+
+	// find the last address with a non-zero balance, and drop the unused tail
+	keep := 0
+	for i, b := range balances {
+		if b.Confirmed.Coins > 0 || b.Predicted.Coins > 0 {
+			keep = i + 1
+		}
+	}
+
+	w.Entries = w.Entries[:int(nExist)+keep]
+	return nil
+}
+
+// Filename returns the wallet's filename
+func (w *Wallet) Filename() string {
+	return w.Meta[metaFilename]
+}
+
+// Label returns the wallet's label
+func (w *Wallet) Label() string {
+	return w.Meta[metaLabel]
+}
+
+func (w *Wallet) setLabel(label string) {
+	w.Meta[metaLabel] = label
+}
+
+// Type returns the wallet's type, e.g. WalletTypeDeterministic
+func (w *Wallet) Type() string {
+	return w.Meta[metaType]
+}
+
+// IsEncrypted returns whether the wallet is encrypted
+func (w *Wallet) IsEncrypted() bool {
+	return w.Meta[metaEncrypted] == "true"
+}
+
+// IsWatchOnly returns whether the wallet holds no seed or private keys
+func (w *Wallet) IsWatchOnly() bool {
+	return w.Type() == WalletTypeXPub
+}
+
+// seed returns the wallet's seed, for use in key derivation. Caller must ensure the wallet is decrypted.
+func (w *Wallet) seed() string {
+	if w.secrets != nil {
+		return w.secrets.seed
+	}
+	return w.Meta[metaSeed]
+}
+
+// seedBytes returns the wallet's seed as the raw bytes used for key derivation:
+// hex-decoded for bip39 wallets (whose Meta[metaSeed] holds the hex-encoded
+// PBKDF2-derived seed), or the seed string's bytes directly otherwise.
+// Caller must ensure the wallet is decrypted.
+func (w *Wallet) seedBytes() ([]byte, error) {
+	seed := w.seed()
+	if w.Meta[metaSeedType] == SeedTypeBip39 {
+		return hex.DecodeString(seed)
+	}
+	return []byte(seed), nil
+}
+
+// displaySeed returns the human-facing representation of the wallet's seed:
+// the bip39 mnemonic for bip39 wallets, or the raw seed otherwise. Caller
+// must ensure the wallet is decrypted.
+func (w *Wallet) displaySeed() string {
+	if w.Meta[metaSeedType] == SeedTypeBip39 {
+		if w.secrets != nil {
+			return w.secrets.mnemonic
+		}
+		return w.Meta[metaMnemonic]
+	}
+	return w.seed()
+}
+
+// clone returns a deep copy of the wallet's Meta and Entries. The decrypted
+// secrets field, if set, is not copied: the clone's secrets is nil.
+func (w *Wallet) clone() *Wallet {
+	nw := &Wallet{
+		Meta:    make(map[string]string, len(w.Meta)),
+		Entries: make([]Entry, len(w.Entries)),
+	}
+	for k, v := range w.Meta {
+		nw.Meta[k] = v
+	}
+	copy(nw.Entries, w.Entries)
+	return nw
+}
+
+// GenerateAddresses generates num new addresses from the wallet's deterministic chain
+func (w *Wallet) GenerateAddresses(num uint64) ([]cipher.Address, error) {
+	if num == 0 {
+		return nil, nil
+	}
+
+	seed := w.Meta[metaLastSeed]
+	if w.secrets != nil {
+		seed = w.secrets.lastSeed
+	}
+
+	newSeed, seckeys := cipher.GenerateDeterministicKeyPairsSeed([]byte(seed), int(num))
+
+	addrs := make([]cipher.Address, 0, num)
+	for _, sk := range seckeys {
+		pk := cipher.MustPubKeyFromSecKey(sk)
+		entry := Entry{
+			Address: cipher.AddressFromPubKey(pk),
+			Public:  pk,
+			Secret:  sk,
+		}
+		w.Entries = append(w.Entries, entry)
+		addrs = append(addrs, entry.Address)
+	}
+
+	if w.secrets != nil {
+		w.secrets.lastSeed = string(newSeed)
+	} else {
+		w.Meta[metaLastSeed] = string(newSeed)
+	}
+
+	return addrs, nil
+}
+
+// GetAddresses returns all addresses in the wallet
+func (w *Wallet) GetAddresses() []cipher.Address {
+	addrs := make([]cipher.Address, len(w.Entries))
+	for i, e := range w.Entries {
+		addrs[i] = e.Address
+	}
+	return addrs
+}
+
+// Save persists the wallet to the given directory
+func (w *Wallet) Save(dir string) error {
+	return save(dir, w)
+}
+
+// GuardUpdate decrypts the wallet with password, runs fn against the decrypted
+// wallet, then re-encrypts the result into w. If fn returns an error, fn's
+// changes are discarded and w is left unchanged and still encrypted.
+func (w *Wallet) GuardUpdate(password []byte, fn func(w *Wallet) error) error {
+	if !w.IsEncrypted() {
+		return ErrWalletNotEncrypted
+	}
+
+	unlocked, err := w.Unlock(password)
+	if err != nil {
+		return err
+	}
+
+	if err := fn(unlocked); err != nil {
+		return err
+	}
+
+	locked, err := unlocked.lockWith(password, CryptoType(w.Meta[metaCryptoType]))
+	if err != nil {
+		return err
+	}
+
+	*w = *locked
+	return nil
+}
+
+// GuardView decrypts the wallet with password and runs fn against the decrypted
+// wallet. The decrypted copy is discarded afterward; it is never persisted.
+func (w *Wallet) GuardView(password []byte, fn func(w *Wallet) error) error {
+	if !w.IsEncrypted() {
+		return ErrWalletNotEncrypted
+	}
+
+	unlocked, err := w.Unlock(password)
+	if err != nil {
+		return err
+	}
+
+	return fn(unlocked)
+}
+
+// CreateAndSignTransaction is a placeholder for creating and signing a
+// transaction from this wallet. Transaction creation is not implemented here:
+// it always returns a "not implemented" error.
+func (w *Wallet) CreateAndSignTransaction(auxs coin.AddressUxOuts, headTime, coins uint64, dest cipher.Address) (*coin.Transaction, error) {
+	return nil, fmt.Errorf("not implemented")
+}
+
+// CreateAndSignTransactionAdvanced is a placeholder for creating and signing a
+// transaction based upon CreateTransactionParams. Transaction creation is not
+// implemented here: it always returns a "not implemented" error.
+func (w *Wallet) CreateAndSignTransactionAdvanced(params CreateTransactionParams, auxs coin.AddressUxOuts, headTime uint64) (*coin.Transaction, []UxBalance, error) {
+	return nil, nil, fmt.Errorf("not implemented")
+}
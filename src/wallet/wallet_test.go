@@ -0,0 +1,144 @@
+package wallet
+
+import (
+	"testing"
+
+	"github.com/skycoin/skycoin/src/cipher"
+)
+
+// TestNewWalletScanAheadEncryptsAfterDerivingAddresses is a regression test for
+// a bug where encryption ran before address derivation, so every encrypted
+// deterministic wallet derived its first address from an empty seed and wallets
+// created from different seeds ended up with identical addresses.
+func TestNewWalletScanAheadEncryptsAfterDerivingAddresses(t *testing.T) {
+	password := []byte("pwd")
+
+	w1, err := NewWalletScanAhead("test1.wlt", Options{
+		Seed:       "seed one",
+		Encrypt:    true,
+		Password:   password,
+		CryptoType: CryptoTypeScryptChacha20poly1305,
+	}, nil)
+	if err != nil {
+		t.Fatalf("NewWalletScanAhead failed: %v", err)
+	}
+
+	w2, err := NewWalletScanAhead("test2.wlt", Options{
+		Seed:       "seed two",
+		Encrypt:    true,
+		Password:   password,
+		CryptoType: CryptoTypeScryptChacha20poly1305,
+	}, nil)
+	if err != nil {
+		t.Fatalf("NewWalletScanAhead failed: %v", err)
+	}
+
+	u1, err := w1.Unlock(password)
+	if err != nil {
+		t.Fatalf("Unlock failed: %v", err)
+	}
+	u2, err := w2.Unlock(password)
+	if err != nil {
+		t.Fatalf("Unlock failed: %v", err)
+	}
+
+	addrs1 := u1.GetAddresses()
+	addrs2 := u2.GetAddresses()
+	if len(addrs1) != 1 || len(addrs2) != 1 {
+		t.Fatalf("expected 1 address each, got %d and %d", len(addrs1), len(addrs2))
+	}
+
+	if addrs1[0] == addrs2[0] {
+		t.Fatal("wallets created from different seeds must not derive the same address")
+	}
+}
+
+// TestLockScrubsAndUnlockRestoresEntrySecret is a regression test for a bug
+// where Lock never cleared Entry.Secret, so an "encrypted" wallet with
+// addresses already generated still had every private key in cleartext.
+// Unlock only borrows the key back into the unexported secrets field (see
+// TestLockWithMergesEntrySecretsFromExistingAndNewEntries for how re-locking
+// carries it over, and TestDecryptInPlaceMaterializesSecretsIntoMeta for how
+// it's written back into Entries).
+func TestLockScrubsAndUnlockRestoresEntrySecret(t *testing.T) {
+	w, err := newWallet("test.wlt", Options{Seed: "a seed"})
+	if err != nil {
+		t.Fatalf("newWallet failed: %v", err)
+	}
+	if _, err := w.GenerateAddresses(1); err != nil {
+		t.Fatalf("GenerateAddresses failed: %v", err)
+	}
+
+	original := w.Entries[0].Secret
+
+	password := []byte("pwd")
+	if err := w.Lock(password, CryptoTypeScryptChacha20poly1305); err != nil {
+		t.Fatalf("Lock failed: %v", err)
+	}
+
+	var zero cipher.SecKey
+	if w.Entries[0].Secret != zero {
+		t.Fatal("Lock must scrub Entry.Secret")
+	}
+
+	unlocked, err := w.Unlock(password)
+	if err != nil {
+		t.Fatalf("Unlock failed: %v", err)
+	}
+
+	if unlocked.Entries[0].Secret != zero {
+		t.Fatal("Unlock must not write the entry secret into the exported Entries field")
+	}
+	if unlocked.secrets.entrySecrets[0] != original {
+		t.Fatal("Unlock must restore the original entry secret into the wallet's secrets field")
+	}
+}
+
+// TestLockWithMergesEntrySecretsFromExistingAndNewEntries is a regression test
+// for a bug where re-locking a transiently-unlocked wallet (e.g. a wallet
+// session, which generates new addresses while unlocked) only captured
+// entry secrets from w.secrets, losing the private keys of any addresses
+// generated since the unlock, which GenerateAddresses always writes straight
+// into Entries rather than into secrets.
+func TestLockWithMergesEntrySecretsFromExistingAndNewEntries(t *testing.T) {
+	w, err := newWallet("test.wlt", Options{Seed: "a seed"})
+	if err != nil {
+		t.Fatalf("newWallet failed: %v", err)
+	}
+	if _, err := w.GenerateAddresses(1); err != nil {
+		t.Fatalf("GenerateAddresses failed: %v", err)
+	}
+
+	password := []byte("pwd")
+	if err := w.Lock(password, CryptoTypeScryptChacha20poly1305); err != nil {
+		t.Fatalf("Lock failed: %v", err)
+	}
+
+	unlocked, err := w.Unlock(password)
+	if err != nil {
+		t.Fatalf("Unlock failed: %v", err)
+	}
+	existingSecret := unlocked.secrets.entrySecrets[0]
+
+	if _, err := unlocked.GenerateAddresses(1); err != nil {
+		t.Fatalf("GenerateAddresses failed: %v", err)
+	}
+	newSecret := unlocked.Entries[1].Secret
+
+	relocked, err := unlocked.lockWith(password, CryptoTypeScryptChacha20poly1305)
+	if err != nil {
+		t.Fatalf("lockWith failed: %v", err)
+	}
+
+	reunlocked, err := relocked.Unlock(password)
+	if err != nil {
+		t.Fatalf("Unlock failed: %v", err)
+	}
+
+	if reunlocked.secrets.entrySecrets[0] != existingSecret {
+		t.Fatal("lockWith lost the pre-existing entry's secret")
+	}
+	if reunlocked.secrets.entrySecrets[1] != newSecret {
+		t.Fatal("lockWith lost the newly generated entry's secret")
+	}
+}
@@ -0,0 +1,191 @@
+package wallet
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+// walletExt is the file extension used for wallet files
+const walletExt = ".wlt"
+
+// tmpWalletExt is the extension used for the temporary file a wallet is
+// written to before being atomically renamed into place
+const tmpWalletExt = walletExt + ".tmp"
+
+// Wallets maps wallet ids (filenames) to wallets
+type Wallets map[string]*Wallet
+
+func (ws Wallets) get(wltID string) (*Wallet, bool) {
+	w, ok := ws[wltID]
+	return w, ok
+}
+
+func (ws Wallets) set(w *Wallet) {
+	ws[w.Filename()] = w
+}
+
+func (ws Wallets) add(w *Wallet) error {
+	if _, ok := ws[w.Filename()]; ok {
+		return fmt.Errorf("wallet %s already exists", w.Filename())
+	}
+	ws[w.Filename()] = w
+	return nil
+}
+
+func (ws Wallets) remove(wltID string) {
+	delete(ws, wltID)
+}
+
+func (ws Wallets) update(wltID string, fn func(w *Wallet) error) error {
+	w, ok := ws[wltID]
+	if !ok {
+		return ErrWalletNotExist
+	}
+	return fn(w)
+}
+
+// LoadWallets loads all .wlt files in dir. If fileLock is true, each file is
+// opened under an advisory exclusive lock, so a concurrent writer (e.g. a GUI
+// wallet running against the same WalletDir) cannot be read mid-write; openTimeout
+// bounds how long to wait for that lock before giving up with ErrWalletLockTimeout.
+func LoadWallets(dir string, openTimeout time.Duration, fileLock bool) (Wallets, error) {
+	entries, err := ioutil.ReadDir(dir)
+	if err != nil {
+		return nil, err
+	}
+
+	wlts := make(Wallets)
+	for _, e := range entries {
+		if e.IsDir() || !strings.HasSuffix(e.Name(), walletExt) {
+			continue
+		}
+
+		w, err := load(filepath.Join(dir, e.Name()), openTimeout, fileLock)
+		if err != nil {
+			return nil, fmt.Errorf("failed to load wallet %s: %v", e.Name(), err)
+		}
+
+		wlts[w.Filename()] = w
+	}
+
+	return wlts, nil
+}
+
+// load reads and unmarshals a single wallet file
+func load(path string, openTimeout time.Duration, fileLock bool) (*Wallet, error) {
+	if fileLock {
+		unlock, err := lockFile(path, openTimeout)
+		if err != nil {
+			return nil, err
+		}
+		defer unlock() //nolint:errcheck
+	}
+
+	data, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	var w Wallet
+	if err := json.Unmarshal(data, &w); err != nil {
+		return nil, err
+	}
+
+	w.Meta[metaFilename] = filepath.Base(path)
+	return &w, nil
+}
+
+// save marshals and writes the wallet to dir/w.Filename(), via a
+// write-to-temp-file, fsync, atomic-rename sequence so a crash or concurrent
+// reader can never observe a partially written wallet file. The temp file is
+// held under the same advisory lock removeBackupFiles checks before deleting
+// a leftover .wlt.tmp, so a concurrent removeBackupFiles run never deletes
+// this file out from under an in-progress write.
+func save(dir string, w *Wallet) error {
+	data, err := json.MarshalIndent(w, "", "    ")
+	if err != nil {
+		return err
+	}
+
+	finalPath := filepath.Join(dir, w.Filename())
+	tmpPath := finalPath + ".tmp"
+
+	// Opened without O_TRUNC: truncating at open time would race a concurrent
+	// writer to the same tmp path, wiping its in-progress buffer before either
+	// side's lock check runs. The file is only truncated once lockFD below
+	// has actually granted this writer exclusive access.
+	f, err := os.OpenFile(tmpPath, os.O_WRONLY|os.O_CREATE, os.FileMode(0600))
+	if err != nil {
+		return err
+	}
+
+	if err := lockFD(f); err != nil {
+		f.Close() //nolint:errcheck
+		return err
+	}
+
+	if err := f.Truncate(0); err != nil {
+		f.Close() //nolint:errcheck
+		return err
+	}
+
+	if _, err := f.Write(data); err != nil {
+		f.Close() //nolint:errcheck
+		return err
+	}
+
+	if err := f.Sync(); err != nil {
+		f.Close() //nolint:errcheck
+		return err
+	}
+
+	if err := f.Close(); err != nil {
+		return err
+	}
+
+	return os.Rename(tmpPath, finalPath)
+}
+
+// removeBackupFiles removes any .wlt.bak files left behind by a previous run,
+// and any .wlt.tmp files left behind by a save that never completed its rename.
+// A .wlt.tmp file currently held under an exclusive lock belongs to an
+// in-progress write from another process, and is left alone rather than destroyed.
+func removeBackupFiles(dir string) error {
+	entries, err := ioutil.ReadDir(dir)
+	if err != nil {
+		return err
+	}
+
+	for _, e := range entries {
+		if e.IsDir() {
+			continue
+		}
+
+		switch {
+		case strings.HasSuffix(e.Name(), walletExt+".bak"):
+			if err := os.Remove(filepath.Join(dir, e.Name())); err != nil {
+				return err
+			}
+
+		case strings.HasSuffix(e.Name(), tmpWalletExt):
+			path := filepath.Join(dir, e.Name())
+			unlock, err := tryLockFile(path)
+			if err != nil {
+				// held by a concurrent writer; leave it alone
+				continue
+			}
+			unlock() //nolint:errcheck
+
+			if err := os.Remove(path); err != nil {
+				return err
+			}
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,287 @@
+package wallet
+
+import (
+	"crypto/hmac"
+	"crypto/sha512"
+	"encoding/binary"
+	"encoding/hex"
+	"fmt"
+	"math/big"
+
+	"github.com/skycoin/skycoin/src/cipher"
+)
+
+// secp256k1 curve parameters, used for CKDpub (BIP32 public-key-only child derivation).
+// This is the same curve as the one backing cipher.PubKey/cipher.SecKey. Point
+// addition and scalar multiplication are implemented directly below rather than
+// via crypto/elliptic: elliptic.CurveParams's generic Add/ScalarBaseMult assume
+// a = -3 (true for the NIST P-curves), while secp256k1 has a = 0, which makes
+// the stdlib's Jacobian formulas produce wrong results (and panic on IsOnCurve).
+var (
+	secp256k1P, _  = new(big.Int).SetString("FFFFFFFFFFFFFFFFFFFFFFFFFFFFFFFFFFFFFFFFFFFFFFFFFFFFFFFEFFFFFC2F", 16)
+	secp256k1Gx, _ = new(big.Int).SetString("79BE667EF9DCBBAC55A06295CE870B07029BFCDB2DCE28D959F2815B16F81798", 16)
+	secp256k1Gy, _ = new(big.Int).SetString("483ADA7726A3C4655DA4FBFC0E1108A8FD17B448A68554199C47D08FFB10D4B8", 16)
+	secp256k1B     = big.NewInt(7)
+)
+
+// pointAdd returns p1 + p2 on the secp256k1 curve, in affine coordinates.
+// A nil x represents the point at infinity.
+func pointAdd(x1, y1, x2, y2 *big.Int) (*big.Int, *big.Int) {
+	if x1 == nil {
+		return x2, y2
+	}
+	if x2 == nil {
+		return x1, y1
+	}
+
+	if x1.Cmp(x2) == 0 {
+		if y1.Sign() == 0 || y1.Cmp(y2) != 0 {
+			// p1 == -p2
+			return nil, nil
+		}
+		return pointDouble(x1, y1)
+	}
+
+	p := secp256k1P
+	num := new(big.Int).Sub(y2, y1)
+	den := new(big.Int).Sub(x2, x1)
+	den.ModInverse(den, p)
+	slope := num.Mul(num, den)
+	slope.Mod(slope, p)
+
+	return pointFromSlope(slope, x1, y1, x2)
+}
+
+// pointDouble returns 2*p on the secp256k1 curve (a = 0, so slope = 3x^2 / 2y)
+func pointDouble(x, y *big.Int) (*big.Int, *big.Int) {
+	if x == nil || y.Sign() == 0 {
+		return nil, nil
+	}
+
+	p := secp256k1P
+	num := new(big.Int).Mul(x, x)
+	num.Mul(num, big.NewInt(3))
+	den := new(big.Int).Lsh(y, 1)
+	den.ModInverse(den, p)
+	slope := num.Mul(num, den)
+	slope.Mod(slope, p)
+
+	return pointFromSlope(slope, x, y, x)
+}
+
+// pointFromSlope completes a point addition/doubling given the slope through
+// (x1, y1) and x2, per the standard short Weierstrass chord-and-tangent formula.
+func pointFromSlope(slope, x1, y1, x2 *big.Int) (*big.Int, *big.Int) {
+	p := secp256k1P
+
+	x3 := new(big.Int).Mul(slope, slope)
+	x3.Sub(x3, x1)
+	x3.Sub(x3, x2)
+	x3.Mod(x3, p)
+
+	y3 := new(big.Int).Sub(x1, x3)
+	y3.Mul(y3, slope)
+	y3.Sub(y3, y1)
+	y3.Mod(y3, p)
+
+	return x3, y3
+}
+
+// scalarMult returns k*(x, y) on the secp256k1 curve via double-and-add.
+func scalarMult(k []byte, x, y *big.Int) (*big.Int, *big.Int) {
+	var rx, ry *big.Int // point at infinity
+
+	n := new(big.Int).SetBytes(k)
+	for i := n.BitLen() - 1; i >= 0; i-- {
+		rx, ry = pointDouble(rx, ry)
+		if n.Bit(i) == 1 {
+			rx, ry = pointAdd(rx, ry, x, y)
+		}
+	}
+
+	return rx, ry
+}
+
+// metaXPub keys, stored alongside the common Meta keys in Wallet.Meta for watch-only wallets
+const (
+	metaXPub           = "xpub"
+	metaXPubPathPrefix = "xpubPathPrefix" // e.g. "m/44'/8000'/0'/0"
+	metaXPubNextIndex  = "xpubNextIndex"
+)
+
+// extendedPubKey is a BIP32 extended public key: a curve point plus a chain code
+type extendedPubKey struct {
+	x, y      *big.Int
+	chainCode []byte
+}
+
+// deriveChild derives the non-hardened child public key at index, via CKDpub.
+// Hardened indices cannot be derived from a public key alone.
+func (k *extendedPubKey) deriveChild(index uint32) (*extendedPubKey, error) {
+	if index >= bip44HardenedOffset {
+		return nil, fmt.Errorf("cannot derive hardened child index %d from an extended public key", index)
+	}
+
+	data := append([]byte{}, compressPoint(k.x, k.y)...)
+	idxBytes := make([]byte, 4)
+	binary.BigEndian.PutUint32(idxBytes, index)
+	data = append(data, idxBytes...)
+
+	mac := hmac.New(sha512.New, k.chainCode)
+	mac.Write(data) //nolint:errcheck
+	sum := mac.Sum(nil)
+
+	il, ir := sum[:32], sum[32:]
+
+	ilX, ilY := scalarMult(il, secp256k1Gx, secp256k1Gy)
+	childX, childY := pointAdd(ilX, ilY, k.x, k.y)
+
+	if childX == nil {
+		return nil, fmt.Errorf("derived public key is invalid, index %d must be skipped", index)
+	}
+
+	return &extendedPubKey{x: childX, y: childY, chainCode: ir}, nil
+}
+
+// pubKey returns the cipher.PubKey (compressed point) for this extended public key
+func (k *extendedPubKey) pubKey() cipher.PubKey {
+	var pk cipher.PubKey
+	copy(pk[:], compressPoint(k.x, k.y))
+	return pk
+}
+
+func compressPoint(x, y *big.Int) []byte {
+	out := make([]byte, 33)
+	if y.Bit(0) == 0 {
+		out[0] = 0x02
+	} else {
+		out[0] = 0x03
+	}
+	xb := x.Bytes()
+	copy(out[33-len(xb):], xb)
+	return out
+}
+
+// decompressPoint recovers the full (x, y) curve point from a compressed
+// 33-byte secp256k1 public key, using that p % 4 == 3 for modular sqrt.
+func decompressPoint(pk []byte) (*big.Int, *big.Int, error) {
+	if len(pk) != 33 || (pk[0] != 0x02 && pk[0] != 0x03) {
+		return nil, nil, fmt.Errorf("invalid compressed public key")
+	}
+
+	p := secp256k1P
+	x := new(big.Int).SetBytes(pk[1:])
+
+	// y^2 = x^3 + 7 mod p
+	ySq := new(big.Int).Exp(x, big.NewInt(3), p)
+	ySq.Add(ySq, secp256k1B)
+	ySq.Mod(ySq, p)
+
+	// p % 4 == 3 for secp256k1, so sqrt(a) = a^((p+1)/4) mod p
+	exp := new(big.Int).Add(p, big.NewInt(1))
+	exp.Div(exp, big.NewInt(4))
+	y := new(big.Int).Exp(ySq, exp, p)
+
+	wantOdd := pk[0] == 0x03
+	if (y.Bit(0) == 1) != wantOdd {
+		y.Sub(p, y)
+	}
+
+	return x, y, nil
+}
+
+// xpubFromSerialized builds the root extendedPubKey for a watch-only wallet from
+// the hex encoding of a 33-byte compressed public key followed by its 32-byte
+// chain code, as persisted in Meta[metaXPub].
+func xpubFromSerialized(s string) (*extendedPubKey, error) {
+	raw, err := hex.DecodeString(s)
+	if err != nil {
+		return nil, fmt.Errorf("invalid xpub encoding: %v", err)
+	}
+	if len(raw) != 33+32 {
+		return nil, fmt.Errorf("invalid xpub length")
+	}
+
+	x, y, err := decompressPoint(raw[:33])
+	if err != nil {
+		return nil, err
+	}
+
+	return &extendedPubKey{x: x, y: y, chainCode: raw[33:]}, nil
+}
+
+func (k *extendedPubKey) serialize() string {
+	return hex.EncodeToString(append(compressPoint(k.x, k.y), k.chainCode...))
+}
+
+// NewXPubWallet creates a watch-only wallet from a serialized extended public
+// key. The wallet holds no seed or private keys and can only derive addresses.
+func NewXPubWallet(wltName string, opts Options) (*Wallet, error) {
+	if opts.XPub == "" {
+		return nil, fmt.Errorf("xpub is required")
+	}
+
+	xpub, err := xpubFromSerialized(opts.XPub)
+	if err != nil {
+		return nil, err
+	}
+
+	pathPrefix := opts.XPubPathPrefix
+	if pathPrefix == "" {
+		pathPrefix = fmt.Sprintf("m/44'/%d'/0'/0", opts.CoinType)
+	}
+
+	w := &Wallet{
+		Meta: map[string]string{
+			metaFilename:       wltName,
+			metaVersion:        Version,
+			metaLabel:          opts.Label,
+			metaType:           WalletTypeXPub,
+			metaCoin:           opts.Coin,
+			metaXPub:           xpub.serialize(),
+			metaXPubPathPrefix: pathPrefix,
+			metaXPubNextIndex:  "0",
+			metaEncrypted:      "false",
+		},
+	}
+
+	if _, err := w.xpubDeriveAddresses(1); err != nil {
+		return nil, err
+	}
+
+	return w, nil
+}
+
+// xpubDeriveAddresses derives num new child public-key addresses, continuing
+// from the wallet's next-unused index, and advances that index.
+func (w *Wallet) xpubDeriveAddresses(num uint64) ([]cipher.Address, error) {
+	xpub, err := xpubFromSerialized(w.Meta[metaXPub])
+	if err != nil {
+		return nil, err
+	}
+
+	var next uint32
+	fmt.Sscanf(w.Meta[metaXPubNextIndex], "%d", &next) //nolint:errcheck
+
+	addrs := make([]cipher.Address, 0, num)
+	for i := uint64(0); i < num; i++ {
+		childIdx := next + uint32(i)
+		child, err := xpub.deriveChild(childIdx)
+		if err != nil {
+			return nil, fmt.Errorf("derive address index %d failed: %v", childIdx, err)
+		}
+
+		pk := child.pubKey()
+		entry := Entry{
+			Address:     cipher.AddressFromPubKey(pk),
+			Public:      pk,
+			ChildNumber: childIdx,
+		}
+		w.Entries = append(w.Entries, entry)
+		addrs = append(addrs, entry.Address)
+	}
+
+	w.Meta[metaXPubNextIndex] = fmt.Sprintf("%d", next+uint32(num))
+
+	return addrs, nil
+}